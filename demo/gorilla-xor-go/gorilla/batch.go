@@ -0,0 +1,124 @@
+package gorilla
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// BatchEncoder encodes N parallel float64 series (one XORChunk per
+// series, e.g. one per metric) that are all sampled on the same tick. Each
+// AppendRow call diffs the new row's bit patterns against the previous
+// row in one crypto/subtle.XORBytes call over a contiguous byte buffer,
+// rather than computing curr^lastVal per series in a Go loop, so the
+// per-tick diff step can benefit from the architecture-specific
+// implementation behind XORBytes. The resulting per-series XOR words are
+// then handed to the normal leading/trailing/meaningful-bits encoder.
+type BatchEncoder struct {
+	chunks []*XORChunk
+	apps   []*Appender
+
+	prev []byte // previous row's 8-byte-per-series float bit patterns
+	curr []byte // this row's bit patterns, rebuilt on every AppendRow
+	xor  []byte // curr XOR prev, one 8-byte word per series
+
+	numRows int
+}
+
+// NewBatchEncoder returns a BatchEncoder ready to accept n parallel
+// series.
+func NewBatchEncoder(n int) *BatchEncoder {
+	chunks := make([]*XORChunk, n)
+	apps := make([]*Appender, n)
+	for i := range chunks {
+		chunks[i] = NewXORChunk()
+		apps[i] = chunks[i].Appender()
+	}
+	return &BatchEncoder{
+		chunks: chunks,
+		apps:   apps,
+		prev:   make([]byte, n*8),
+		curr:   make([]byte, n*8),
+		xor:    make([]byte, n*8),
+	}
+}
+
+// AppendRow encodes one (t, values) row, where values[i] belongs to series
+// i. len(values) must equal the series count passed to NewBatchEncoder.
+func (e *BatchEncoder) AppendRow(t int64, values []float64) {
+	if len(values) != len(e.apps) {
+		panic(fmt.Sprintf("gorilla: AppendRow got %d values, want %d", len(values), len(e.apps)))
+	}
+
+	for i, v := range values {
+		binary.BigEndian.PutUint64(e.curr[i*8:(i+1)*8], math.Float64bits(v))
+	}
+
+	if e.numRows == 0 {
+		for i, v := range values {
+			e.apps[i].Append(t, v)
+		}
+	} else {
+		subtle.XORBytes(e.xor, e.curr, e.prev)
+		for i, v := range values {
+			vDelta := binary.BigEndian.Uint64(e.xor[i*8 : (i+1)*8])
+			e.apps[i].appendXOR(t, v, vDelta)
+		}
+	}
+
+	e.prev, e.curr = e.curr, e.prev
+	e.numRows++
+}
+
+// Chunks returns the underlying per-series chunks, one per series index.
+func (e *BatchEncoder) Chunks() []*XORChunk {
+	return e.chunks
+}
+
+// Iterator returns a BatchIterator reading every series back from the
+// start, row by row.
+func (e *BatchEncoder) Iterator() *BatchIterator {
+	its := make([]*Iterator, len(e.chunks))
+	for i, c := range e.chunks {
+		its[i] = c.Iterator()
+	}
+	return &BatchIterator{its: its}
+}
+
+// BatchIterator reads the rows written by a BatchEncoder back out,
+// advancing every series' Iterator in lockstep.
+type BatchIterator struct {
+	its    []*Iterator
+	values []float64
+}
+
+// Next decodes the next row and returns its timestamp and per-series
+// values. It returns ok=false once any series is exhausted. The returned
+// values slice is reused across calls; copy it if it must outlive the next
+// call to Next.
+func (bi *BatchIterator) Next() (t int64, values []float64, ok bool) {
+	if bi.values == nil {
+		bi.values = make([]float64, len(bi.its))
+	}
+	for i, it := range bi.its {
+		tt, v, ok := it.Next()
+		if !ok {
+			return 0, nil, false
+		}
+		t = tt
+		bi.values[i] = v
+	}
+	return t, bi.values, true
+}
+
+// Err returns the first error encountered while decoding any series, if
+// any.
+func (bi *BatchIterator) Err() error {
+	for _, it := range bi.its {
+		if err := it.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package gorilla
+
+import (
+	"fmt"
+	"testing"
+)
+
+var batchSeriesCounts = []int{64, 256, 1024}
+
+// scalarAppendRow is what BatchEncoder.AppendRow replaces: a plain Go loop
+// of Appender.Append calls, each computing its own curr^lastVal XOR. It's
+// kept only so BenchmarkBatchAppendRow can compare against the vectorized
+// subtle.XORBytes path.
+func scalarAppendRow(apps []*Appender, t int64, values []float64) {
+	for i, v := range values {
+		apps[i].Append(t, v)
+	}
+}
+
+func BenchmarkBatchAppendRow(b *testing.B) {
+	const rows = 64
+
+	for _, n := range batchSeriesCounts {
+		values := series("random_walk", n)
+
+		b.Run(fmt.Sprintf("n=%d/scalar", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				chunks := make([]*XORChunk, n)
+				apps := make([]*Appender, n)
+				for j := range chunks {
+					chunks[j] = NewXORChunk()
+					apps[j] = chunks[j].Appender()
+				}
+				for r := 0; r < rows; r++ {
+					scalarAppendRow(apps, int64(r), values)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("n=%d/batch", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				enc := NewBatchEncoder(n)
+				for r := 0; r < rows; r++ {
+					enc.AppendRow(int64(r), values)
+				}
+			}
+		})
+	}
+}
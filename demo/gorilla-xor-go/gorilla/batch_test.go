@@ -0,0 +1,58 @@
+package gorilla
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBatchEncoderRoundTrip(t *testing.T) {
+	rows := [][]float64{
+		{1, 2, 3},
+		{1, 2.5, 3},
+		{1, 2.5, 3.1},
+		{-1, 0, math.Inf(1)},
+	}
+
+	// Timestamps chosen so the dod between rows 2 and 3 lands exactly on the
+	// 7-bit varbit field boundary (64), not just a constant cadence.
+	timestamps := []int64{0, 1000, 2000, 3064}
+
+	enc := NewBatchEncoder(3)
+	for i, row := range rows {
+		enc.AppendRow(timestamps[i], row)
+	}
+
+	it := enc.Iterator()
+	for i, want := range rows {
+		ts, got, ok := it.Next()
+		if !ok {
+			t.Fatalf("row %d: Next() = false, want true", i)
+		}
+		if ts != timestamps[i] {
+			t.Errorf("row %d: t = %d, want %d", i, ts, timestamps[i])
+		}
+		if len(got) != len(want) {
+			t.Fatalf("row %d: got %d values, want %d", i, len(got), len(want))
+		}
+		for s := range want {
+			if math.Float64bits(got[s]) != math.Float64bits(want[s]) {
+				t.Errorf("row %d series %d: got %v, want %v", i, s, got[s], want[s])
+			}
+		}
+	}
+	if _, _, ok := it.Next(); ok {
+		t.Fatalf("Next() after last row = true, want false")
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+}
+
+func TestBatchEncoderAppendRowWrongWidth(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("AppendRow with wrong width did not panic")
+		}
+	}()
+	NewBatchEncoder(3).AppendRow(0, []float64{1, 2})
+}
@@ -0,0 +1,151 @@
+package gorilla
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// countLeadingZerosLoop and countTrailingZerosLoop are the original
+// bit-at-a-time implementations, kept here only so the benchmarks below
+// can show how much countLeadingZeros/countTrailingZeros gained by
+// switching to math/bits.
+func countLeadingZerosLoop(v uint64) int {
+	var n int
+	for i := 63; i >= 0; i-- {
+		if (v>>uint(i))&1 == 1 {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func countTrailingZerosLoop(v uint64) int {
+	var n int
+	for i := 0; i < 64; i++ {
+		if (v>>uint(i))&1 == 1 {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// series returns a few representative time-series shapes used throughout
+// the benchmarks below: a monotonic counter (e.g. a request count), a
+// smooth sine wave (e.g. a periodic gauge), and a random walk (e.g. noisy
+// sensor data).
+func series(name string, n int) []float64 {
+	out := make([]float64, n)
+	switch name {
+	case "counter":
+		for i := range out {
+			out[i] = float64(i) * 3
+		}
+	case "sine":
+		for i := range out {
+			out[i] = math.Sin(float64(i)/10) * 100
+		}
+	case "random_walk":
+		rnd := rand.New(rand.NewSource(42))
+		v := 0.0
+		for i := range out {
+			v += rnd.NormFloat64()
+			out[i] = v
+		}
+	}
+	return out
+}
+
+var seriesNames = []string{"counter", "sine", "random_walk"}
+
+func xorDeltas(values []float64) []uint64 {
+	deltas := make([]uint64, 0, len(values))
+	var last uint64
+	for i, v := range values {
+		bits := math.Float64bits(v)
+		if i > 0 {
+			deltas = append(deltas, bits^last)
+		}
+		last = bits
+	}
+	return deltas
+}
+
+func BenchmarkCountZeros(b *testing.B) {
+	for _, name := range seriesNames {
+		deltas := xorDeltas(series(name, 1024))
+
+		b.Run(name+"/loop", func(b *testing.B) {
+			var sink int
+			for i := 0; i < b.N; i++ {
+				for _, d := range deltas {
+					sink += countLeadingZerosLoop(d) + countTrailingZerosLoop(d)
+				}
+			}
+			_ = sink
+		})
+
+		b.Run(name+"/math_bits", func(b *testing.B) {
+			var sink int
+			for i := 0; i < b.N; i++ {
+				for _, d := range deltas {
+					sink += countLeadingZeros(d) + countTrailingZeros(d)
+				}
+			}
+			_ = sink
+		})
+	}
+}
+
+func BenchmarkAppend(b *testing.B) {
+	for _, name := range seriesNames {
+		values := series(name, 1024)
+
+		b.Run(name+"/xor64", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				app := NewXORChunk().Appender()
+				for j, v := range values {
+					app.Append(int64(j), v)
+				}
+			}
+		})
+
+		b.Run(name+"/xor32", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				app := NewXOR32Chunk().Appender()
+				for j, v := range values {
+					app.Append(int64(j), float32(v))
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCompressionRatio isn't a timing benchmark; it reports the
+// encoded size per sample as a custom metric so `go test -bench` makes the
+// 32-bit win visible alongside the timing numbers above.
+func BenchmarkCompressionRatio(b *testing.B) {
+	for _, name := range seriesNames {
+		values := series(name, 1024)
+
+		b.Run(name+"/xor64", func(b *testing.B) {
+			chunk := NewXORChunk()
+			app := chunk.Appender()
+			for j, v := range values {
+				app.Append(int64(j), v)
+			}
+			b.ReportMetric(float64(len(chunk.Bytes()))/float64(len(values)), "bytes/sample")
+		})
+
+		b.Run(name+"/xor32", func(b *testing.B) {
+			chunk := NewXOR32Chunk()
+			app := chunk.Appender()
+			for j, v := range values {
+				app.Append(int64(j), float32(v))
+			}
+			b.ReportMetric(float64(len(chunk.Bytes()))/float64(len(values)), "bytes/sample")
+		})
+	}
+}
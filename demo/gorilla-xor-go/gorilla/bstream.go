@@ -0,0 +1,115 @@
+package gorilla
+
+import "io"
+
+// bit is a single bit, used to make writeBit calls read as prose.
+type bit bool
+
+const (
+	zero bit = false
+	one  bit = true
+)
+
+// bstream is an append-only bit stream backed by a []byte plus a running
+// bit offset, and a cursor for reading the bits back out in order. Writes
+// always happen at the end of the stream; reads always happen at readOffset.
+// This is the same design Prometheus' tsdb/chunkenc package uses for its
+// Gorilla-encoded chunks.
+type bstream struct {
+	stream []byte
+	count  uint8 // number of free bits in the last byte of stream
+
+	readOffset int   // byte index of the next byte to read
+	readCount  uint8 // number of unread bits in stream[readOffset]
+}
+
+// newBStream returns an empty bstream ready for writing.
+func newBStream() *bstream {
+	return &bstream{}
+}
+
+// newBReader returns a bstream positioned to read back a previously written
+// byte slice.
+func newBReader(b []byte) *bstream {
+	return &bstream{stream: b}
+}
+
+// bytes returns the underlying byte slice written so far.
+func (b *bstream) bytes() []byte {
+	return b.stream
+}
+
+// writeBit appends a single bit to the stream.
+func (b *bstream) writeBit(bit bit) {
+	if b.count == 0 {
+		b.stream = append(b.stream, 0)
+		b.count = 8
+	}
+	i := len(b.stream) - 1
+	if bit {
+		b.stream[i] |= 1 << (b.count - 1)
+	}
+	b.count--
+}
+
+// writeByte appends a full byte to the stream, byte-aligned or not.
+func (b *bstream) writeByte(byt byte) {
+	if b.count == 0 {
+		b.stream = append(b.stream, 0)
+		b.count = 8
+	}
+	i := len(b.stream) - 1
+	b.stream[i] |= byt >> (8 - b.count)
+	b.stream = append(b.stream, byt<<b.count)
+}
+
+// writeBits appends the nbits least-significant bits of u to the stream,
+// most-significant bit first.
+func (b *bstream) writeBits(u uint64, nbits int) {
+	u <<= 64 - uint(nbits)
+	for nbits >= 8 {
+		b.writeByte(byte(u >> 56))
+		u <<= 8
+		nbits -= 8
+	}
+	for nbits > 0 {
+		b.writeBit((u >> 63) == 1)
+		u <<= 1
+		nbits--
+	}
+}
+
+// readBit reads the next bit off the stream.
+func (b *bstream) readBit() (bit, error) {
+	if b.readCount == 0 {
+		if b.readOffset >= len(b.stream) {
+			return zero, io.EOF
+		}
+		b.readCount = 8
+	}
+	byt := b.stream[b.readOffset]
+	d := (byt >> (b.readCount - 1)) & 1
+	b.readCount--
+	if b.readCount == 0 {
+		b.readOffset++
+	}
+	return d != 0, nil
+}
+
+// readBits reads the next n bits off the stream and returns them as the
+// low n bits of the returned uint64.
+func (b *bstream) readBits(n int) (uint64, error) {
+	var u uint64
+	for n > 0 {
+		bit, err := b.readBit()
+		if err != nil {
+			return 0, err
+		}
+		u <<= 1
+		if bit {
+			u |= 1
+		}
+		n--
+	}
+	return u, nil
+}
@@ -0,0 +1,193 @@
+package gorilla
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// WindowPolicy encodes and decodes the value half of a Gorilla-framed
+// chunk: everything except the bitstream, the delta-of-delta timestamps
+// and the chunk's sample-count header, which stay the same regardless of
+// which policy is in use. A policy instance is stateful and owned by a
+// single Appender or Iterator: it tracks whatever history it needs (the
+// previous value and leading/trailing window for GorillaPolicy, a ring of
+// recent values for ChimpPolicy) across successive Encode/Decode calls.
+type WindowPolicy interface {
+	// Encode writes the next sample's raw bit pattern (vbits) to bw.
+	Encode(bw *bstream, vbits uint64)
+
+	// Decode reads back one value written by Encode and returns its raw
+	// bit pattern.
+	Decode(br *bstream) (vbits uint64, err error)
+}
+
+// GorillaPolicy is the classic Facebook Gorilla value codec: XOR against
+// the immediately preceding value, with leading/trailing "window" reuse
+// via encodeGorillaXOR/decodeGorillaXOR. It is equivalent to the codec
+// built into Appender/Iterator by default; it exists as a WindowPolicy so
+// it can be swapped for e.g. ChimpPolicy through AppenderWithPolicy /
+// IteratorWithPolicy.
+type GorillaPolicy struct {
+	hasLast bool
+	last    uint64
+
+	hasWindow         bool
+	leading, trailing uint8
+}
+
+// NewGorillaPolicy returns a GorillaPolicy ready to encode or decode a
+// chunk from the start.
+func NewGorillaPolicy() *GorillaPolicy {
+	return &GorillaPolicy{}
+}
+
+func (p *GorillaPolicy) Encode(bw *bstream, vbits uint64) {
+	if !p.hasLast {
+		bw.writeBits(vbits, 64)
+		p.last, p.hasLast = vbits, true
+		return
+	}
+	xor := vbits ^ p.last
+	p.last = vbits
+	p.leading, p.trailing, p.hasWindow = encodeGorillaXOR(bw, xor, p.hasWindow, p.leading, p.trailing)
+}
+
+func (p *GorillaPolicy) Decode(br *bstream) (uint64, error) {
+	if !p.hasLast {
+		v, err := br.readBits(64)
+		if err != nil {
+			return 0, err
+		}
+		p.last, p.hasLast = v, true
+		return v, nil
+	}
+	xor, leading, trailing, windowSet, err := decodeGorillaXOR(br, p.hasWindow, p.leading, p.trailing)
+	if err != nil {
+		return 0, err
+	}
+	p.leading, p.trailing, p.hasWindow = leading, trailing, windowSet
+	p.last ^= xor
+	return p.last, nil
+}
+
+// Chimp-style parameters: a ring of the last chimpRingSize values, indexed
+// by a chimpIndexBits-bit field (log2(chimpRingSize)).
+const (
+	chimpRingSize  = 128
+	chimpIndexBits = 7
+)
+
+// ChimpPolicy is a Chimp128-style value codec: instead of always XORing
+// against the immediately preceding value, it keeps a ring of the last
+// chimpRingSize values and XORs the new value against whichever ring entry
+// produces the smallest XOR (the most leading zeros), writing that entry's
+// index in chimpIndexBits bits. It also stores the trailing-zero count
+// directly rather than a meaningful-bits length.
+type ChimpPolicy struct {
+	ring   [chimpRingSize]uint64
+	filled int
+	next   int
+}
+
+// NewChimpPolicy returns a ChimpPolicy ready to encode or decode a chunk
+// from the start.
+func NewChimpPolicy() *ChimpPolicy {
+	return &ChimpPolicy{}
+}
+
+func (p *ChimpPolicy) push(vbits uint64) {
+	p.ring[p.next] = vbits
+	p.next = (p.next + 1) % chimpRingSize
+	if p.filled < chimpRingSize {
+		p.filled++
+	}
+}
+
+// best returns the filled ring index whose value XORs against vbits with
+// the most leading zeros (the closest match), along with that XOR.
+func (p *ChimpPolicy) best(vbits uint64) (idx int, xor uint64) {
+	bestLeading := -1
+	for i := 0; i < p.filled; i++ {
+		x := vbits ^ p.ring[i]
+		if l := bits.LeadingZeros64(x); l > bestLeading {
+			bestLeading, idx, xor = l, i, x
+		}
+	}
+	return idx, xor
+}
+
+func (p *ChimpPolicy) Encode(bw *bstream, vbits uint64) {
+	if p.filled == 0 {
+		bw.writeBits(vbits, 64)
+		p.push(vbits)
+		return
+	}
+
+	idx, xor := p.best(vbits)
+	bw.writeBits(uint64(idx), chimpIndexBits)
+
+	if xor == 0 {
+		bw.writeBit(zero)
+		p.push(vbits)
+		return
+	}
+	bw.writeBit(one)
+
+	leading := uint8(bits.LeadingZeros64(xor))
+	trailing := uint8(bits.TrailingZeros64(xor))
+	meaningful := 64 - leading - trailing
+	bw.writeBits(uint64(leading), 6)
+	bw.writeBits(uint64(trailing), 6)
+	bw.writeBits(xor>>trailing, int(meaningful))
+
+	p.push(vbits)
+}
+
+func (p *ChimpPolicy) Decode(br *bstream) (uint64, error) {
+	if p.filled == 0 {
+		v, err := br.readBits(64)
+		if err != nil {
+			return 0, err
+		}
+		p.push(v)
+		return v, nil
+	}
+
+	idxBits, err := br.readBits(chimpIndexBits)
+	if err != nil {
+		return 0, err
+	}
+	idx := int(idxBits)
+	if idx >= p.filled {
+		return 0, fmt.Errorf("gorilla: chimp ring index %d out of range (filled=%d)", idx, p.filled)
+	}
+	ref := p.ring[idx]
+
+	cb, err := br.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if cb == zero {
+		p.push(ref)
+		return ref, nil
+	}
+
+	leadingBits, err := br.readBits(6)
+	if err != nil {
+		return 0, err
+	}
+	trailingBits, err := br.readBits(6)
+	if err != nil {
+		return 0, err
+	}
+	leading, trailing := uint8(leadingBits), uint8(trailingBits)
+	meaningful := 64 - leading - trailing
+	payload, err := br.readBits(int(meaningful))
+	if err != nil {
+		return 0, err
+	}
+
+	v := ref ^ (payload << trailing)
+	p.push(v)
+	return v, nil
+}
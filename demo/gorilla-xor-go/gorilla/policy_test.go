@@ -0,0 +1,75 @@
+package gorilla
+
+import (
+	"math"
+	"testing"
+)
+
+func policyEncodeDecode(t *testing.T, newPolicy func() WindowPolicy, samples []sample) ([]sample, int) {
+	t.Helper()
+
+	chunk := NewXORChunk()
+	app := chunk.AppenderWithPolicy(newPolicy())
+	for _, s := range samples {
+		app.Append(s.t, s.v)
+	}
+
+	var got []sample
+	it := chunk.IteratorWithPolicy(newPolicy())
+	for {
+		ts, v, ok := it.Next()
+		if !ok {
+			break
+		}
+		got = append(got, sample{ts, v})
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iterator.Err() = %v", err)
+	}
+	return got, len(chunk.Bytes())
+}
+
+// TestWindowPolicyConformance round-trips identical inputs through both
+// WindowPolicy implementations and reports each one's compression ratio.
+func TestWindowPolicyConformance(t *testing.T) {
+	policies := []struct {
+		name string
+		new  func() WindowPolicy
+	}{
+		{"gorilla", func() WindowPolicy { return NewGorillaPolicy() }},
+		{"chimp", func() WindowPolicy { return NewChimpPolicy() }},
+	}
+
+	for _, seriesName := range seriesNames {
+		values := series(seriesName, 256)
+		samples := make([]sample, len(values))
+		cur := int64(0)
+		for i, v := range values {
+			samples[i] = sample{cur, v}
+			// Vary the step per sample (rather than holding it constant)
+			// so dod is not always zero: it is +17 within each 13-sample
+			// cycle and -204 where the cycle wraps.
+			cur += 15 + int64(i%13)*17
+		}
+
+		for _, p := range policies {
+			t.Run(seriesName+"/"+p.name, func(t *testing.T) {
+				got, bytes := policyEncodeDecode(t, p.new, samples)
+				if len(got) != len(samples) {
+					t.Fatalf("got %d samples, want %d", len(got), len(samples))
+				}
+				for i, want := range samples {
+					if got[i].t != want.t {
+						t.Errorf("sample %d: t = %d, want %d", i, got[i].t, want.t)
+					}
+					gb, wb := math.Float64bits(got[i].v), math.Float64bits(want.v)
+					if gb != wb {
+						t.Errorf("sample %d: v = %v (bits %x), want %v (bits %x)", i, got[i].v, gb, want.v, wb)
+					}
+				}
+				t.Logf("%s/%s: %d bytes for %d samples (%.2f bytes/sample)",
+					seriesName, p.name, bytes, len(samples), float64(bytes)/float64(len(samples)))
+			})
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package gorilla
+
+// Timestamps are encoded as delta-of-delta (dod) against the previous two
+// samples, using the variable-length prefix scheme from the Facebook
+// Gorilla paper: the smaller the dod, the fewer bits it costs. Each
+// payload width n stores dod as a plain n-bit two's-complement value, so
+// the range per width is exactly what n-bit two's complement can hold.
+//
+//	dod == 0            -> '0'
+//	dod in [-64, 63]     -> '10'   + 7  bits
+//	dod in [-256, 255]   -> '110'  + 9  bits
+//	dod in [-2048, 2047] -> '1110' + 12 bits
+//	otherwise            -> '1111' + 64 bits
+//
+// The final tier stores the full 64-bit dod rather than truncating to a
+// narrower fixed width, so arbitrary int64 timestamp sequences always
+// round-trip.
+const (
+	dodBitsSmall  = 7
+	dodBitsMedium = 9
+	dodBitsLarge  = 12
+	dodBitsHuge   = 64
+)
+
+// putVarbitTimestamp appends dod to bw using the prefix scheme above.
+func putVarbitTimestamp(bw *bstream, dod int64) {
+	switch {
+	case dod == 0:
+		bw.writeBit(zero)
+	case -64 <= dod && dod <= 63:
+		bw.writeBits(0b10, 2)
+		bw.writeBits(uint64(dod), dodBitsSmall)
+	case -256 <= dod && dod <= 255:
+		bw.writeBits(0b110, 3)
+		bw.writeBits(uint64(dod), dodBitsMedium)
+	case -2048 <= dod && dod <= 2047:
+		bw.writeBits(0b1110, 4)
+		bw.writeBits(uint64(dod), dodBitsLarge)
+	default:
+		bw.writeBits(0b1111, 4)
+		bw.writeBits(uint64(dod), dodBitsHuge)
+	}
+}
+
+// readVarbitTimestamp reads back a dod written by putVarbitTimestamp. It
+// reads the unary-ish prefix (up to three leading '1' bits terminated by a
+// '0', or four '1' bits with no terminator) to learn how many payload bits
+// follow.
+func readVarbitTimestamp(br *bstream) (int64, error) {
+	var prefix byte
+	for i := 0; i < 4; i++ {
+		prefix <<= 1
+		b, err := br.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if b == zero {
+			break
+		}
+		prefix |= 1
+	}
+
+	var nbits int
+	switch prefix {
+	case 0b0:
+		return 0, nil
+	case 0b10:
+		nbits = dodBitsSmall
+	case 0b110:
+		nbits = dodBitsMedium
+	case 0b1110:
+		nbits = dodBitsLarge
+	case 0b1111:
+		nbits = dodBitsHuge
+	}
+
+	bits, err := br.readBits(nbits)
+	if err != nil {
+		return 0, err
+	}
+	return signExtend(bits, nbits), nil
+}
+
+// signExtend interprets the low n bits of v as a two's-complement signed
+// integer of width n and sign-extends it to an int64.
+func signExtend(v uint64, n int) int64 {
+	shift := uint(64 - n)
+	return int64(v<<shift) >> shift
+}
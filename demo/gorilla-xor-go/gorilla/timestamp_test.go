@@ -0,0 +1,31 @@
+package gorilla
+
+import "testing"
+
+func TestVarbitTimestampBoundaries(t *testing.T) {
+	cases := []int64{
+		0,
+		1, -1,
+		63, 64, -64, -65, // 7-bit field boundary
+		255, 256, -256, -257, // 9-bit field boundary
+		2047, 2048, -2048, -2049, // 12-bit field boundary
+		1 << 30, -(1 << 30), // falls into the huge field
+		1<<31 - 1, -(1 << 31), 1 << 31, -(1<<31 + 1), // int32 boundary
+		5_000_000_000, -5_000_000_000, // exceeds int32 range
+		1 << 62, -(1 << 62), // near int64 range
+	}
+
+	for _, dod := range cases {
+		bw := newBStream()
+		putVarbitTimestamp(bw, dod)
+
+		br := newBReader(bw.bytes())
+		got, err := readVarbitTimestamp(br)
+		if err != nil {
+			t.Fatalf("dod=%d: readVarbitTimestamp() error = %v", dod, err)
+		}
+		if got != dod {
+			t.Errorf("dod=%d: round-tripped as %d", dod, got)
+		}
+	}
+}
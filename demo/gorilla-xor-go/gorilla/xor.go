@@ -0,0 +1,347 @@
+// Package gorilla implements the Facebook Gorilla time-series compression
+// scheme (delta-of-delta timestamps, XOR'd float values), following the
+// design used by the Prometheus TSDB chunk encoder.
+package gorilla
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// chunkHeaderSize is the width, in bytes, of the sample-count header that
+// precedes every chunk's bit-packed samples.
+const chunkHeaderSize = 2
+
+// XORChunk holds an in-progress or fully written Gorilla-encoded series of
+// (timestamp, value) samples. The first chunkHeaderSize bytes hold the
+// number of samples written so far; the rest is the bit-packed stream.
+type XORChunk struct {
+	b *bstream
+}
+
+// NewXORChunk returns an empty chunk ready to be appended to.
+func NewXORChunk() *XORChunk {
+	b := newBStream()
+	b.stream = append(b.stream, make([]byte, chunkHeaderSize)...)
+	return &XORChunk{b: b}
+}
+
+// Bytes returns the encoded chunk contents.
+func (c *XORChunk) Bytes() []byte {
+	return c.b.bytes()
+}
+
+// NumSamples returns the number of samples written to the chunk.
+func (c *XORChunk) NumSamples() int {
+	return int(binary.BigEndian.Uint16(c.b.stream[:chunkHeaderSize]))
+}
+
+// Appender returns a fresh Appender writing to the end of this chunk using
+// the classic Gorilla value codec.
+func (c *XORChunk) Appender() *Appender {
+	return &Appender{b: c.b, numSamples: c.NumSamples()}
+}
+
+// AppenderWithPolicy returns a fresh Appender whose value codec is
+// delegated to policy instead of the classic Gorilla one built into
+// Appender. The bitstream, delta-of-delta timestamps and chunk framing are
+// unchanged; only the value encoding swaps. An Iterator reading this chunk
+// back must be given an equivalent, freshly constructed policy via
+// IteratorWithPolicy.
+func (c *XORChunk) AppenderWithPolicy(policy WindowPolicy) *Appender {
+	return &Appender{b: c.b, numSamples: c.NumSamples(), policy: policy}
+}
+
+// Iterator returns a fresh Iterator reading this chunk from the start
+// using the classic Gorilla value codec.
+func (c *XORChunk) Iterator() *Iterator {
+	br := newBReader(c.b.bytes())
+	br.readOffset = chunkHeaderSize
+	return &Iterator{br: br, total: c.NumSamples()}
+}
+
+// IteratorWithPolicy returns a fresh Iterator reading this chunk back with
+// policy as its value codec. policy must be the same kind, in the same
+// initial state, as whatever policy AppenderWithPolicy used to write the
+// chunk.
+func (c *XORChunk) IteratorWithPolicy(policy WindowPolicy) *Iterator {
+	br := newBReader(c.b.bytes())
+	br.readOffset = chunkHeaderSize
+	return &Iterator{br: br, total: c.NumSamples(), policy: policy}
+}
+
+// Appender encodes (t, v) samples onto a chunk one at a time, keeping just
+// enough state to compute each sample's delta-of-delta and XOR.
+type Appender struct {
+	b *bstream
+
+	// policy, when set, replaces the value codec below entirely; see
+	// AppenderWithPolicy.
+	policy WindowPolicy
+
+	numSamples int
+
+	t, tDelta int64
+	v         float64
+
+	leading  uint8
+	trailing uint8
+}
+
+// Append encodes a single (t, v) sample. Samples must be appended in
+// increasing order of t.
+func (a *Appender) Append(t int64, v float64) {
+	a.writeTimestamp(t)
+	switch {
+	case a.policy != nil:
+		a.policy.Encode(a.b, math.Float64bits(v))
+	case a.numSamples == 0:
+		a.b.writeBits(math.Float64bits(v), 64)
+	default:
+		a.writeVDelta(v)
+	}
+	a.advance(t, v)
+}
+
+// writeTimestamp encodes t: raw on the first sample, a raw 64-bit delta on
+// the second, and a delta-of-delta varbit thereafter.
+func (a *Appender) writeTimestamp(t int64) {
+	switch a.numSamples {
+	case 0:
+		a.b.writeBits(uint64(t), 64)
+	case 1:
+		a.tDelta = t - a.t
+		a.b.writeBits(uint64(a.tDelta), 64)
+	default:
+		tDelta := t - a.t
+		putVarbitTimestamp(a.b, tDelta-a.tDelta)
+		a.tDelta = tDelta
+	}
+}
+
+// advance records t and v as the most recently appended sample and bumps
+// the chunk's sample-count header.
+func (a *Appender) advance(t int64, v float64) {
+	a.t = t
+	a.v = v
+	a.numSamples++
+	binary.BigEndian.PutUint16(a.b.stream[:chunkHeaderSize], uint16(a.numSamples))
+}
+
+// writeVDelta XORs v's bit pattern against the previous value and dispatches
+// the result to writeXORDelta.
+func (a *Appender) writeVDelta(v float64) {
+	a.writeXORDelta(math.Float64bits(v) ^ math.Float64bits(a.v))
+}
+
+// appendXOR is Append's counterpart for callers (namely BatchEncoder) that
+// have already computed the value XOR themselves, e.g. via a single
+// vectorized diff across many series. a must already have at least one
+// sample; a fresh chunk's first sample has no prior value to XOR against
+// and must go through Append instead.
+func (a *Appender) appendXOR(t int64, v float64, vDelta uint64) {
+	a.writeTimestamp(t)
+	a.writeXORDelta(vDelta)
+	a.advance(t, v)
+}
+
+// writeXORDelta encodes an already-computed value XOR using encodeGorillaXOR,
+// keeping a's leading/trailing window up to date for the next call.
+func (a *Appender) writeXORDelta(vDelta uint64) {
+	leading, trailing, _ := encodeGorillaXOR(a.b, vDelta, a.numSamples > 1, a.leading, a.trailing)
+	a.leading, a.trailing = leading, trailing
+}
+
+// Iterator reads back the (t, v) samples written by an Appender.
+type Iterator struct {
+	br    *bstream
+	total int
+
+	// policy, when set, replaces the value codec below entirely; see
+	// IteratorWithPolicy.
+	policy WindowPolicy
+
+	numRead int
+
+	t, tDelta int64
+	v         float64
+
+	leading  uint8
+	trailing uint8
+
+	err error
+}
+
+// Next decodes and returns the next sample in the chunk. It returns
+// (0, 0, false) once the chunk is exhausted or a decode error occurs; Err
+// reports the latter case.
+func (it *Iterator) Next() (int64, float64, bool) {
+	if it.err != nil || it.numRead >= it.total {
+		return 0, 0, false
+	}
+
+	switch it.numRead {
+	case 0:
+		t, err := it.br.readBits(64)
+		if err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+		it.t = int64(t)
+	case 1:
+		tDelta, err := it.br.readBits(64)
+		if err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+		it.tDelta = int64(tDelta)
+		it.t += it.tDelta
+	default:
+		dod, err := readVarbitTimestamp(it.br)
+		if err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+		it.tDelta += dod
+		it.t += it.tDelta
+	}
+
+	switch {
+	case it.policy != nil:
+		vbits, err := it.policy.Decode(it.br)
+		if err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+		it.v = math.Float64frombits(vbits)
+	case it.numRead == 0:
+		v, err := it.br.readBits(64)
+		if err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+		it.v = math.Float64frombits(v)
+	default:
+		if err := it.readVDelta(); err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+	}
+
+	it.numRead++
+	return it.t, it.v, true
+}
+
+// readVDelta mirrors writeXORDelta: it decodes one value XOR via
+// decodeGorillaXOR, keeping it's leading/trailing window up to date.
+func (it *Iterator) readVDelta() error {
+	xor, leading, trailing, _, err := decodeGorillaXOR(it.br, it.numRead > 1, it.leading, it.trailing)
+	if err != nil {
+		return err
+	}
+	it.leading, it.trailing = leading, trailing
+	it.v = math.Float64frombits(math.Float64bits(it.v) ^ xor)
+	return nil
+}
+
+// Err returns the first error encountered while decoding, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// encodeGorillaXOR writes an already-computed value XOR using the classic
+// Gorilla control-bit scheme:
+//
+//	xor == 0                         -> '0'
+//	xor inside the previous window   -> '10' + meaningful bits
+//	otherwise                        -> '11' + 5-bit leading count
+//	                                     + 6-bit meaningful-bits length
+//	                                     + meaningful bits
+//
+// hasWindow, prevLeading and prevTrailing describe the window left behind
+// by the previous non-zero delta, if any; it returns the (possibly
+// unchanged) window to pass back in on the next call. This is the shared
+// value codec behind both the default Appender/Iterator fast path and
+// GorillaPolicy.
+func encodeGorillaXOR(bw *bstream, xor uint64, hasWindow bool, prevLeading, prevTrailing uint8) (leading, trailing uint8, windowSet bool) {
+	if xor == 0 {
+		bw.writeBit(zero)
+		return prevLeading, prevTrailing, hasWindow
+	}
+	bw.writeBit(one)
+
+	leading = uint8(countLeadingZeros(xor))
+	trailing = uint8(countTrailingZeros(xor))
+
+	// Clamp leading to the 5-bit field used below so an XOR whose upper 32
+	// bits are all zero (leading >= 32) can't overflow it.
+	if leading >= 32 {
+		leading = 31
+	}
+
+	if hasWindow && leading >= prevLeading && trailing >= prevTrailing {
+		bw.writeBit(zero)
+		meaningful := 64 - prevLeading - prevTrailing
+		bw.writeBits(xor>>prevTrailing, int(meaningful))
+		return prevLeading, prevTrailing, true
+	}
+
+	bw.writeBit(one)
+
+	// meaningful ranges over [1, 64], so store meaningful-1 to fit the
+	// 6-bit field (which only holds 0-63).
+	meaningful := 64 - leading - trailing
+	bw.writeBits(uint64(leading), 5)
+	bw.writeBits(uint64(meaningful-1), 6)
+	bw.writeBits(xor>>trailing, int(meaningful))
+	return leading, trailing, true
+}
+
+// decodeGorillaXOR reads back one value XOR written by encodeGorillaXOR.
+func decodeGorillaXOR(br *bstream, hasWindow bool, prevLeading, prevTrailing uint8) (xor uint64, leading, trailing uint8, windowSet bool, err error) {
+	cb, err := br.readBit()
+	if err != nil {
+		return 0, prevLeading, prevTrailing, hasWindow, err
+	}
+	if cb == zero {
+		return 0, prevLeading, prevTrailing, hasWindow, nil
+	}
+
+	newWindow, err := br.readBit()
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+
+	leading, trailing, windowSet = prevLeading, prevTrailing, hasWindow
+	if newWindow == one {
+		lb, err := br.readBits(5)
+		if err != nil {
+			return 0, 0, 0, false, err
+		}
+		mb, err := br.readBits(6)
+		if err != nil {
+			return 0, 0, 0, false, err
+		}
+		leading = uint8(lb)
+		trailing = 64 - leading - (uint8(mb) + 1)
+		windowSet = true
+	}
+
+	meaningful := 64 - leading - trailing
+	payload, err := br.readBits(int(meaningful))
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+	return payload << trailing, leading, trailing, windowSet, nil
+}
+
+// countLeadingZeros returns the number of leading zero bits in v.
+func countLeadingZeros(v uint64) int {
+	return bits.LeadingZeros64(v)
+}
+
+// countTrailingZeros returns the number of trailing zero bits in v.
+func countTrailingZeros(v uint64) int {
+	return bits.TrailingZeros64(v)
+}
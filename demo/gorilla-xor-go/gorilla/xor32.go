@@ -0,0 +1,228 @@
+package gorilla
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// XOR32Chunk is the float32 counterpart to XORChunk: same delta-of-delta
+// timestamps and chunk framing, but a narrower value codec sized for
+// 32-bit floats (4-bit leading count, 5-bit meaningful-bits length).
+type XOR32Chunk struct {
+	b *bstream
+}
+
+// NewXOR32Chunk returns an empty chunk ready to be appended to.
+func NewXOR32Chunk() *XOR32Chunk {
+	b := newBStream()
+	b.stream = append(b.stream, make([]byte, chunkHeaderSize)...)
+	return &XOR32Chunk{b: b}
+}
+
+// Bytes returns the encoded chunk contents.
+func (c *XOR32Chunk) Bytes() []byte {
+	return c.b.bytes()
+}
+
+// NumSamples returns the number of samples written to the chunk.
+func (c *XOR32Chunk) NumSamples() int {
+	return int(binary.BigEndian.Uint16(c.b.stream[:chunkHeaderSize]))
+}
+
+// Appender returns a fresh Appender32 writing to the end of this chunk.
+func (c *XOR32Chunk) Appender() *Appender32 {
+	return &Appender32{b: c.b, numSamples: c.NumSamples()}
+}
+
+// Iterator returns a fresh Iterator32 reading this chunk from the start.
+func (c *XOR32Chunk) Iterator() *Iterator32 {
+	br := newBReader(c.b.bytes())
+	br.readOffset = chunkHeaderSize
+	return &Iterator32{br: br, total: c.NumSamples()}
+}
+
+// Appender32 encodes (t, v) samples with v as a float32, using a narrower
+// value codec than Appender's float64 one.
+type Appender32 struct {
+	b *bstream
+
+	numSamples int
+
+	t, tDelta int64
+	v         float32
+
+	leading  uint8
+	trailing uint8
+}
+
+// Append encodes a single (t, v) sample. Samples must be appended in
+// increasing order of t; v is stored bit-for-bit.
+func (a *Appender32) Append(t int64, v float32) {
+	switch a.numSamples {
+	case 0:
+		a.b.writeBits(uint64(t), 64)
+		a.b.writeBits(uint64(math.Float32bits(v)), 32)
+	case 1:
+		a.tDelta = t - a.t
+		a.b.writeBits(uint64(a.tDelta), 64)
+		a.writeVDelta(v)
+	default:
+		tDelta := t - a.t
+		putVarbitTimestamp(a.b, tDelta-a.tDelta)
+		a.tDelta = tDelta
+		a.writeVDelta(v)
+	}
+
+	a.t = t
+	a.v = v
+	a.numSamples++
+	binary.BigEndian.PutUint16(a.b.stream[:chunkHeaderSize], uint16(a.numSamples))
+}
+
+// writeVDelta mirrors Appender.writeVDelta but with 4-bit leading and
+// 5-bit meaningful-length fields, sized for 32-bit values.
+func (a *Appender32) writeVDelta(v float32) {
+	vDelta := math.Float32bits(v) ^ math.Float32bits(a.v)
+
+	if vDelta == 0 {
+		a.b.writeBit(zero)
+		return
+	}
+	a.b.writeBit(one)
+
+	leading := uint8(bits.LeadingZeros32(vDelta))
+	trailing := uint8(bits.TrailingZeros32(vDelta))
+
+	// Clamp leading to the 4-bit field used below.
+	if leading >= 16 {
+		leading = 15
+	}
+
+	if a.numSamples > 1 && leading >= a.leading && trailing >= a.trailing {
+		a.b.writeBit(zero)
+		meaningful := 32 - a.leading - a.trailing
+		a.b.writeBits(uint64(vDelta>>a.trailing), int(meaningful))
+		return
+	}
+
+	a.b.writeBit(one)
+	a.leading = leading
+	a.trailing = trailing
+
+	// meaningful ranges over [1, 32], so store meaningful-1 to fit the
+	// 5-bit field (which only holds 0-31).
+	meaningful := 32 - leading - trailing
+	a.b.writeBits(uint64(leading), 4)
+	a.b.writeBits(uint64(meaningful-1), 5)
+	a.b.writeBits(uint64(vDelta>>trailing), int(meaningful))
+}
+
+// Iterator32 reads back the (t, v) samples written by an Appender32.
+type Iterator32 struct {
+	br    *bstream
+	total int
+
+	numRead int
+
+	t, tDelta int64
+	v         float32
+
+	leading  uint8
+	trailing uint8
+
+	err error
+}
+
+// Next decodes and returns the next sample in the chunk. It returns
+// (0, 0, false) once the chunk is exhausted or a decode error occurs; Err
+// reports the latter case.
+func (it *Iterator32) Next() (int64, float32, bool) {
+	if it.err != nil || it.numRead >= it.total {
+		return 0, 0, false
+	}
+
+	switch it.numRead {
+	case 0:
+		t, err := it.br.readBits(64)
+		if err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+		v, err := it.br.readBits(32)
+		if err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+		it.t = int64(t)
+		it.v = math.Float32frombits(uint32(v))
+	case 1:
+		tDelta, err := it.br.readBits(64)
+		if err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+		it.tDelta = int64(tDelta)
+		it.t += it.tDelta
+		if err := it.readVDelta(); err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+	default:
+		dod, err := readVarbitTimestamp(it.br)
+		if err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+		it.tDelta += dod
+		it.t += it.tDelta
+		if err := it.readVDelta(); err != nil {
+			it.err = err
+			return 0, 0, false
+		}
+	}
+
+	it.numRead++
+	return it.t, it.v, true
+}
+
+func (it *Iterator32) readVDelta() error {
+	cb, err := it.br.readBit()
+	if err != nil {
+		return err
+	}
+	if cb == zero {
+		return nil
+	}
+
+	newWindow, err := it.br.readBit()
+	if err != nil {
+		return err
+	}
+	if newWindow == one {
+		leading, err := it.br.readBits(4)
+		if err != nil {
+			return err
+		}
+		meaningful, err := it.br.readBits(5)
+		if err != nil {
+			return err
+		}
+		it.leading = uint8(leading)
+		it.trailing = 32 - it.leading - (uint8(meaningful) + 1)
+	}
+
+	meaningful := 32 - it.leading - it.trailing
+	bits, err := it.br.readBits(int(meaningful))
+	if err != nil {
+		return err
+	}
+	vbits := math.Float32bits(it.v) ^ (uint32(bits) << it.trailing)
+	it.v = math.Float32frombits(vbits)
+	return nil
+}
+
+// Err returns the first error encountered while decoding, if any.
+func (it *Iterator32) Err() error {
+	return it.err
+}
@@ -0,0 +1,158 @@
+package gorilla
+
+import (
+	"math"
+	"testing"
+)
+
+type sample struct {
+	t int64
+	v float64
+}
+
+func encodeDecode(t *testing.T, samples []sample) []sample {
+	t.Helper()
+
+	chunk := NewXORChunk()
+	app := chunk.Appender()
+	for _, s := range samples {
+		app.Append(s.t, s.v)
+	}
+
+	var got []sample
+	it := chunk.Iterator()
+	for {
+		ts, v, ok := it.Next()
+		if !ok {
+			break
+		}
+		got = append(got, sample{ts, v})
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iterator.Err() = %v", err)
+	}
+	return got
+}
+
+func TestXORChunkRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		samples []sample
+	}{
+		{
+			name:    "empty",
+			samples: nil,
+		},
+		{
+			name:    "single sample",
+			samples: []sample{{1000, 1.5}},
+		},
+		{
+			name:    "two samples",
+			samples: []sample{{1000, 1.5}, {1015, 2.5}},
+		},
+		{
+			name: "constant value",
+			samples: []sample{
+				{1000, 12.5}, {1015, 12.5}, {1030, 12.5}, {1045, 12.5},
+			},
+		},
+		{
+			name: "shared window",
+			samples: []sample{
+				{1000, 12.5}, {1015, 12.5}, {1030, 12.6}, {1045, 12.6}, {1060, 12.8},
+			},
+		},
+		{
+			name: "irregular timestamps",
+			samples: []sample{
+				{0, 1}, {1, 2}, {3, 4}, {100, 8}, {10000, 16}, {10001, 32},
+			},
+		},
+		{
+			// Deltas chosen so consecutive dods land exactly on the 7/9/12-bit
+			// varbit field boundaries (±64, ±256, ±2048).
+			name: "dod boundaries",
+			samples: []sample{
+				{0, 1}, {1000, 2}, {2000, 3}, {3064, 4}, {4064, 5},
+				{5320, 6}, {6320, 7}, {9368, 8}, {10368, 9},
+			},
+		},
+		{
+			// The dod between the second and third samples exceeds int32
+			// range, exercising the huge (64-bit) varbit tier.
+			name: "huge dod",
+			samples: []sample{
+				{0, 1}, {1000, 2}, {5_000_001_000, 3},
+			},
+		},
+		{
+			name: "negative and special values",
+			samples: []sample{
+				{0, -1.5},
+				{10, math.Inf(1)},
+				{20, math.Inf(-1)},
+				{30, 0},
+				{40, -0.0},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := encodeDecode(t, tc.samples)
+			if len(got) != len(tc.samples) {
+				t.Fatalf("got %d samples, want %d", len(got), len(tc.samples))
+			}
+			for i, want := range tc.samples {
+				if got[i].t != want.t {
+					t.Errorf("sample %d: t = %d, want %d", i, got[i].t, want.t)
+				}
+				gb, wb := math.Float64bits(got[i].v), math.Float64bits(want.v)
+				if gb != wb {
+					t.Errorf("sample %d: v = %v (bits %x), want %v (bits %x)", i, got[i].v, gb, want.v, wb)
+				}
+			}
+		})
+	}
+}
+
+func FuzzXORChunkRoundTrip(f *testing.F) {
+	f.Add(int64(1000), 12.5, int64(15), 0.1)
+	f.Add(int64(0), 0.0, int64(1), -0.0)
+	f.Add(int64(-100), math.Inf(1), int64(1000000), math.NaN())
+
+	f.Fuzz(func(t *testing.T, startT int64, startV float64, tStep int64, vStep float64) {
+		if tStep < 0 {
+			tStep = -tStep
+		}
+		n := 50
+
+		var samples []sample
+		cur := startT
+		v := startV
+		for i := 0; i < n; i++ {
+			samples = append(samples, sample{cur, v})
+			// Vary the step per sample (rather than holding it constant for
+			// the whole run) so dod is not always zero: it is +17 within
+			// each 13-sample cycle and -204 where the cycle wraps.
+			cur += tStep%100000 + int64(i%13)*17
+			v += vStep
+		}
+
+		got := encodeDecode(t, samples)
+		if len(got) != len(samples) {
+			t.Fatalf("got %d samples, want %d", len(got), len(samples))
+		}
+		for i, want := range samples {
+			if got[i].t != want.t {
+				t.Fatalf("sample %d: t = %d, want %d", i, got[i].t, want.t)
+			}
+			gb, wb := math.Float64bits(got[i].v), math.Float64bits(want.v)
+			if gb != wb {
+				t.Fatalf("sample %d: v = %v (bits %x), want %v (bits %x)", i, got[i].v, gb, want.v, wb)
+			}
+		}
+	})
+}
@@ -1,58 +1,43 @@
+// Command gorilla-xor-go demonstrates the gorilla package by appending a
+// small series of (timestamp, value) samples to an XORChunk and then
+// reading them back out through an Iterator.
 package main
 
 import (
 	"fmt"
-	"math"
+
+	"github.com/winter-loo/devnotes/demo/gorilla-xor-go/gorilla"
 )
 
-// Minimal Gorilla XOR encoding demonstration
 func main() {
-	values := []f64{12.5, 12.5, 12.6, 12.6, 12.8}
-	var lastVal uint64
-	var first = true
-
-	fmt.Println("Value | XOR Result | Leading Zeros | Trailing Zeros | Decision")
-	fmt.Println("----------------------------------------------------------------")
-
-	for _, v := range values {
-		curr := math.Float64bits(v)
-		if first {
-			lastVal = curr
-			first = false
-			fmt.Printf("%5.1f | (Initial)  | -             | -              | Store full 64 bits\n", v)
-			continue
-		}
-
-		xor := curr ^ lastVal
-		if xor == 0 {
-			fmt.Printf("%5.1f | 0x%016x | -             | -              | Store '0'\n", v, xor)
-		} else {
-			lz := countLeadingZeros(xor)
-			tz := countTrailingZeros(xor)
-			fmt.Printf("%5.1f | 0x%016x | %-13d | %-14d | Store '1' + Metadata + Meaningful Bits\n", v, xor, lz, tz)
-		}
-		lastVal = curr
+	samples := []struct {
+		t int64
+		v float64
+	}{
+		{1000, 12.5},
+		{1015, 12.5},
+		{1030, 12.6},
+		{1045, 12.6},
+		{1060, 12.8},
 	}
-}
 
-func countLeadingZeros(v uint64) int {
-	var n int
-	for i := 63; i >= 0; i-- {
-		if (v >> i) & 1 == 1 {
-			break
-		}
-		n++
+	chunk := gorilla.NewXORChunk()
+	app := chunk.Appender()
+	for _, s := range samples {
+		app.Append(s.t, s.v)
 	}
-	return n
-}
 
-func countTrailingZeros(v uint64) int {
-	var n int
-	for i := 0; i < 64; i++ {
-		if (v >> i) & 1 == 1 {
+	fmt.Printf("encoded %d samples into %d bytes\n", len(samples), len(chunk.Bytes()))
+
+	it := chunk.Iterator()
+	for {
+		t, v, ok := it.Next()
+		if !ok {
 			break
 		}
-		n++
+		fmt.Printf("t=%d v=%v\n", t, v)
+	}
+	if err := it.Err(); err != nil {
+		fmt.Println("decode error:", err)
 	}
-	return n
 }